@@ -0,0 +1,167 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+
+	"github.com/emersion/go-message"
+)
+
+// SelectAlternative picks the best-matching part out of a set of
+// alternatives, such as the candidates returned by Reader.SelectAlternative.
+//
+// prefer lists content types from most to least wanted, e.g.
+// []string{"text/html", "text/plain"}. When none of parts match any entry
+// in prefer, SelectAlternative falls back to the RFC 2046 rule that later
+// parts are a richer rendition of earlier ones, and returns the last one.
+func SelectAlternative(parts []*Part, prefer []string) (*Part, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("mail: no alternative part to select from")
+	}
+
+	for _, want := range prefer {
+		for i := len(parts) - 1; i >= 0; i-- {
+			mediaType, _, err := partContentType(parts[i].Header)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(mediaType, want) {
+				return parts[i], nil
+			}
+		}
+	}
+
+	return parts[len(parts)-1], nil
+}
+
+// SelectAlternative finds the multipart/alternative subtree in r (whether r
+// itself is one, or it's wrapped in a multipart/mixed alongside
+// attachments) and returns the best-matching leaf per prefer (see
+// SelectAlternative), plus a sibling map of any embedded resources
+// reachable from that leaf, keyed by their Content-ID.
+//
+// Nested alternatives are resolved recursively. When a branch is a
+// multipart/related rather than a plain leaf, its first part is used as
+// the candidate for that branch, and the other parts of that
+// multipart/related are returned in the sibling map so the caller can
+// still resolve "cid:" references from the winning part.
+//
+// SelectAlternative reads r's underlying body to the end, the same way
+// NextPart does. Call either SelectAlternative or NextPart on a given
+// Reader, never both.
+func (r *Reader) SelectAlternative(prefer ...string) (*Part, map[string]*Part, error) {
+	return selectAlternativeEntity(r.e, prefer)
+}
+
+func selectAlternativeEntity(e *message.Entity, prefer []string) (*Part, map[string]*Part, error) {
+	mediaType, _, _ := e.Header.ContentType()
+
+	switch mediaType {
+	case "multipart/mixed":
+		mr := e.MultipartReader()
+		for {
+			child, err := mr.NextPart()
+			if err == io.EOF {
+				return nil, nil, errors.New("mail: multipart/mixed has no body part to select an alternative from")
+			} else if err != nil {
+				return nil, nil, err
+			}
+
+			disp, _, _ := mime.ParseMediaType(child.Header.Get("Content-Disposition"))
+			if disp == "attachment" {
+				continue
+			}
+			return selectAlternativeEntity(child, prefer)
+		}
+
+	case "multipart/alternative":
+		mr := e.MultipartReader()
+
+		var candidates []*Part
+		candidateSiblings := make(map[*Part]map[string]*Part)
+		for {
+			child, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, nil, err
+			}
+
+			p, childSiblings, err := selectAlternativeEntity(child, prefer)
+			if err != nil {
+				return nil, nil, err
+			}
+			candidateSiblings[p] = childSiblings
+			candidates = append(candidates, p)
+		}
+
+		best, err := SelectAlternative(candidates, prefer)
+		if err != nil {
+			return nil, nil, err
+		}
+		return best, candidateSiblings[best], nil
+
+	case "multipart/related":
+		return resolveRelated(e, prefer)
+
+	default:
+		body, err := ioutil.ReadAll(e.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &Part{Header: partHeaderFor(e), Body: bytes.NewReader(body)}, nil, nil
+	}
+}
+
+// resolveRelated splits a multipart/related entity into its root document
+// and a sibling map of its other parts, keyed by Content-ID.
+//
+// Each part's body must be read before resolveRelated asks the underlying
+// MultipartReader for the next one, so the root is resolved as soon as
+// it's encountered (recursing into it right away) and every other part is
+// buffered into memory immediately, rather than being held onto as a
+// *message.Entity for later.
+func resolveRelated(e *message.Entity, prefer []string) (*Part, map[string]*Part, error) {
+	mr := e.MultipartReader()
+
+	siblings := make(map[string]*Part)
+	var rootPart *Part
+	var rootSiblings map[string]*Part
+	for i := 0; ; i++ {
+		child, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		if i == 0 {
+			rootPart, rootSiblings, err = selectAlternativeEntity(child, prefer)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		body, err := ioutil.ReadAll(child.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cid := strings.Trim(child.Header.Get("Content-Id"), "<>"); cid != "" {
+			siblings[cid] = &Part{Header: partHeaderFor(child), Body: bytes.NewReader(body)}
+		}
+	}
+	if rootPart == nil {
+		return nil, nil, fmt.Errorf("mail: empty multipart/related")
+	}
+	for cid, sp := range rootSiblings {
+		siblings[cid] = sp
+	}
+
+	return rootPart, siblings, nil
+}
@@ -0,0 +1,72 @@
+package mail_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message/mail"
+)
+
+func readPart(t *testing.T, p *mail.Part) string {
+	t.Helper()
+	b, err := ioutil.ReadAll(p.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(p.Body) = %v", err)
+	}
+	return string(b)
+}
+
+func TestReaderSelectAlternative_plain(t *testing.T) {
+	mr, err := mail.CreateReader(strings.NewReader(alternativeWithAttachmentMailString))
+	if err != nil {
+		t.Fatalf("mail.CreateReader() = %v", err)
+	}
+	defer mr.Close()
+
+	p, siblings, err := mr.SelectAlternative("text/html", "text/plain")
+	if err != nil {
+		t.Fatalf("mr.SelectAlternative() = %v", err)
+	}
+	if len(siblings) != 0 {
+		t.Errorf("len(siblings) = %v, want 0", len(siblings))
+	}
+	if got := readPart(t, p); got != "<p>Who are you?</p>" {
+		t.Errorf("readPart(p) = %q, want %q", got, "<p>Who are you?</p>")
+	}
+}
+
+func TestReaderSelectAlternative_preferText(t *testing.T) {
+	mr, err := mail.CreateReader(strings.NewReader(alternativeWithAttachmentMailString))
+	if err != nil {
+		t.Fatalf("mail.CreateReader() = %v", err)
+	}
+	defer mr.Close()
+
+	p, _, err := mr.SelectAlternative("text/plain", "text/html")
+	if err != nil {
+		t.Fatalf("mr.SelectAlternative() = %v", err)
+	}
+	if got := readPart(t, p); got != "Who are you?" {
+		t.Errorf("readPart(p) = %q, want %q", got, "Who are you?")
+	}
+}
+
+func TestReaderSelectAlternative_related(t *testing.T) {
+	mr, err := mail.CreateReader(strings.NewReader(relatedMailString))
+	if err != nil {
+		t.Fatalf("mail.CreateReader() = %v", err)
+	}
+	defer mr.Close()
+
+	p, siblings, err := mr.SelectAlternative("text/html")
+	if err != nil {
+		t.Fatalf("mr.SelectAlternative() = %v", err)
+	}
+	if got := readPart(t, p); got != `<img src="cid:img1@local">` {
+		t.Errorf("readPart(p) = %q, want %q", got, `<img src="cid:img1@local">`)
+	}
+	if _, ok := siblings["img1@local"]; !ok {
+		t.Errorf("siblings[%q] missing, got %v", "img1@local", siblings)
+	}
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// ContentID returns the Content-ID of this attachment, with the angle
+// brackets stripped, or "" if the header has none.
+func (h *AttachmentHeader) ContentID() (string, error) {
+	raw := h.Header.Get("Content-Id")
+	if raw == "" {
+		return "", nil
+	}
+	return strings.Trim(raw, "<>"), nil
+}
+
+// CIDMap walks r and indexes every part that carries a Content-ID, keyed by
+// that ID with the angle brackets stripped. It's meant for resolving
+// "cid:" references from an HTML body, e.g. together with RewriteCIDs,
+// without every caller having to write the same Content-ID parsing loop.
+//
+// Like WalkParts, CIDMap consumes r: call it instead of, not in addition
+// to, iterating r.NextPart() yourself. Unlike WalkParts, the parts in the
+// returned map are meant to be read after CIDMap returns, so their bodies
+// are buffered into memory while still inside the WalkParts callback.
+func (r *Reader) CIDMap() (map[string]*Part, error) {
+	cids := make(map[string]*Part)
+	err := r.WalkParts(func(path []string, p *Part) error {
+		cid := strings.Trim(p.Header.Get("Content-Id"), "<>")
+		if cid == "" {
+			return nil
+		}
+		body, err := ioutil.ReadAll(p.Body)
+		if err != nil {
+			return err
+		}
+		cids[cid] = &Part{Header: p.Header, Body: bytes.NewReader(body)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cids, nil
+}
+
+var cidRefPattern = regexp.MustCompile(`(?i)cid:([A-Za-z0-9._%+\-@]+)`)
+
+// RewriteCIDs rewrites every "cid:" reference in an HTML body (such as
+// <img src="cid:abc123@host">) using resolve, which is typically backed by
+// the map returned by CIDMap and turns a CID into a "data:" URI built from
+// the matching embedded file. References for which resolve reports
+// ok == false are left untouched, so partially-resolvable mail still
+// renders.
+func RewriteCIDs(html []byte, resolve func(cid string) (dataURI string, ok bool)) ([]byte, error) {
+	out := cidRefPattern.ReplaceAllFunc(html, func(match []byte) []byte {
+		cid := string(match[len("cid:"):])
+		if dataURI, ok := resolve(cid); ok {
+			return []byte(dataURI)
+		}
+		return match
+	})
+	return out, nil
+}
@@ -0,0 +1,50 @@
+package mail_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message/mail"
+)
+
+func TestReaderCIDMap(t *testing.T) {
+	mr, err := mail.CreateReader(strings.NewReader(relatedMailString))
+	if err != nil {
+		t.Fatalf("mail.CreateReader() = %v", err)
+	}
+	defer mr.Close()
+
+	cids, err := mr.CIDMap()
+	if err != nil {
+		t.Fatalf("mr.CIDMap() = %v", err)
+	}
+
+	p, ok := cids["img1@local"]
+	if !ok {
+		t.Fatalf("cids[%q] missing, got %v", "img1@local", cids)
+	}
+	if got := readPart(t, p); got != "\x89PNG" {
+		t.Errorf("readPart(p) = %q, want %q", got, "\x89PNG")
+	}
+}
+
+func TestRewriteCIDs(t *testing.T) {
+	html := []byte(`<img src="cid:img1@local"><img src="cid:missing@local">`)
+
+	resolve := func(cid string) (string, bool) {
+		if cid == "img1@local" {
+			return "data:image/png;base64,iVBORw0KGgo=", true
+		}
+		return "", false
+	}
+
+	got, err := mail.RewriteCIDs(html, resolve)
+	if err != nil {
+		t.Fatalf("mail.RewriteCIDs() = %v", err)
+	}
+
+	want := `<img src="data:image/png;base64,iVBORw0KGgo="><img src="cid:missing@local">`
+	if string(got) != want {
+		t.Errorf("mail.RewriteCIDs() = %q, want %q", got, want)
+	}
+}
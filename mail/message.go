@@ -0,0 +1,163 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+// Attachment is a non-inline part of a message, such as a file the sender
+// chose to attach to their message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// EmbeddedFile is an inline resource referenced from the HTML body via a
+// "cid:" URI, for instance an image displayed inline in an HTML e-mail.
+type EmbeddedFile struct {
+	CID         string
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a mail message flattened into its most commonly used parts.
+// It is produced by Parse, which walks the possibly deeply nested
+// multipart/mixed, multipart/alternative and multipart/related structure of
+// a message so that callers don't have to. The same type doubles as a
+// builder: set its fields and call WriteTo or Bytes to marshal it back into
+// RFC 5322 bytes, with Parse and WriteTo round-tripping cleanly.
+type Message struct {
+	Header Header
+
+	From []*netmail.Address
+	To   []*netmail.Address
+	Cc   []*netmail.Address
+	Bcc  []*netmail.Address
+
+	Subject   string
+	Date      time.Time
+	InReplyTo string
+
+	TextBody string
+	HTMLBody string
+
+	Attachments   []Attachment
+	EmbeddedFiles []EmbeddedFile
+}
+
+// Parse reads a mail message from r and flattens it into a Message.
+//
+// Inside multipart/alternative, both the plain text and the HTML
+// representation are kept (in TextBody and HTMLBody respectively), so
+// callers can fall back to plain text when they can't render HTML. Parts
+// with a Content-Disposition of "attachment" end up in Attachments, even
+// when they carry a Content-ID. Other parts with a Content-ID, or with an
+// inline disposition and a filename inside a multipart/related, end up in
+// EmbeddedFiles, keyed by that CID, so they can later be resolved from
+// "cid:" references in the HTML body. Everything else with no better home
+// (including any text/plain or text/html part after the first of its kind)
+// ends up in Attachments.
+func Parse(r io.Reader) (*Message, error) {
+	mr, err := CreateReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("mail: failed to parse message: %v", err)
+	}
+	defer mr.Close()
+
+	m := &Message{Header: mr.Header}
+	err = mr.WalkParts(func(path []string, p *Part) error {
+		return m.addPart(path, p)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mail: failed to parse message: %v", err)
+	}
+	return m, nil
+}
+
+// ParseMessage is an alias for Parse.
+func ParseMessage(r io.Reader) (*Message, error) {
+	return Parse(r)
+}
+
+func (m *Message) addPart(path []string, p *Part) error {
+	mediaType, _, err := partContentType(p.Header)
+	if err != nil {
+		return err
+	}
+
+	// Get is part of the PartHeader interface itself, so it works
+	// regardless of whether the reader classified this part as inline or
+	// as an attachment.
+	disp, dispParams, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	cid := strings.Trim(p.Header.Get("Content-Id"), "<>")
+
+	insideRelated := len(path) > 0 && path[len(path)-1] == "multipart/related"
+
+	if disp == "attachment" {
+		return m.addAttachmentOrEmbedded(mediaType, filename, cid, false, p.Body)
+	}
+
+	// A part is embedded if it's addressable by CID, or if it's an inline
+	// resource of a multipart/related referenced by filename instead (some
+	// senders omit Content-Id and point at attachments by name).
+	if cid != "" || (insideRelated && disp == "inline" && filename != "") {
+		return m.addAttachmentOrEmbedded(mediaType, filename, cid, true, p.Body)
+	}
+
+	switch mediaType {
+	case "text/plain":
+		if m.TextBody != "" {
+			return m.addAttachmentOrEmbedded(mediaType, filename, cid, false, p.Body)
+		}
+		b, err := ioutil.ReadAll(p.Body)
+		if err != nil {
+			return err
+		}
+		m.TextBody = string(b)
+		return nil
+	case "text/html":
+		if m.HTMLBody != "" {
+			return m.addAttachmentOrEmbedded(mediaType, filename, cid, false, p.Body)
+		}
+		b, err := ioutil.ReadAll(p.Body)
+		if err != nil {
+			return err
+		}
+		m.HTMLBody = string(b)
+		return nil
+	default:
+		// No better home for it: treat it as an attachment, even without an
+		// explicit Content-Disposition.
+		return m.addAttachmentOrEmbedded(mediaType, filename, cid, false, p.Body)
+	}
+}
+
+func (m *Message) addAttachmentOrEmbedded(mediaType, filename, cid string, embedded bool, body io.Reader) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if embedded {
+		m.EmbeddedFiles = append(m.EmbeddedFiles, EmbeddedFile{
+			CID:         cid,
+			Filename:    filename,
+			ContentType: mediaType,
+			Data:        data,
+		})
+		return nil
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Data:        data,
+	})
+	return nil
+}
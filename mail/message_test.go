@@ -0,0 +1,142 @@
+package mail_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message/mail"
+)
+
+func TestParse_nonMultipart(t *testing.T) {
+	s := "Subject: Your Name\r\n" +
+		"\r\n" +
+		"Who are you?"
+
+	m, err := mail.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("mail.Parse() = %v", err)
+	}
+
+	if m.TextBody != "Who are you?" {
+		t.Errorf("m.TextBody = %q, want %q", m.TextBody, "Who are you?")
+	}
+	if m.HTMLBody != "" {
+		t.Errorf("m.HTMLBody = %q, want empty", m.HTMLBody)
+	}
+}
+
+const alternativeWithAttachmentMailString = "Subject: Your Name\r\n" +
+	"Content-Type: multipart/mixed; boundary=MIXED\r\n" +
+	"\r\n" +
+	"--MIXED\r\n" +
+	"Content-Type: multipart/alternative; boundary=ALT\r\n" +
+	"\r\n" +
+	"--ALT\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Who are you?\r\n" +
+	"--ALT\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>Who are you?</p>\r\n" +
+	"--ALT--\r\n" +
+	"--MIXED\r\n" +
+	"Content-Disposition: attachment; filename=note.txt\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"I'm Mitsuha.\r\n" +
+	"--MIXED--\r\n"
+
+func TestParse_alternativeWithAttachment(t *testing.T) {
+	m, err := mail.Parse(strings.NewReader(alternativeWithAttachmentMailString))
+	if err != nil {
+		t.Fatalf("mail.Parse() = %v", err)
+	}
+
+	if m.TextBody != "Who are you?" {
+		t.Errorf("m.TextBody = %q, want %q", m.TextBody, "Who are you?")
+	}
+	if m.HTMLBody != "<p>Who are you?</p>" {
+		t.Errorf("m.HTMLBody = %q, want %q", m.HTMLBody, "<p>Who are you?</p>")
+	}
+
+	if len(m.Attachments) != 1 {
+		t.Fatalf("len(m.Attachments) = %v, want 1", len(m.Attachments))
+	}
+	if m.Attachments[0].Filename != "note.txt" {
+		t.Errorf("m.Attachments[0].Filename = %q, want %q", m.Attachments[0].Filename, "note.txt")
+	}
+	if string(m.Attachments[0].Data) != "I'm Mitsuha." {
+		t.Errorf("m.Attachments[0].Data = %q, want %q", m.Attachments[0].Data, "I'm Mitsuha.")
+	}
+
+	if len(m.EmbeddedFiles) != 0 {
+		t.Errorf("len(m.EmbeddedFiles) = %v, want 0", len(m.EmbeddedFiles))
+	}
+}
+
+const relatedMailString = "Subject: Your Name\r\n" +
+	"Content-Type: multipart/related; boundary=REL\r\n" +
+	"\r\n" +
+	"--REL\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<img src=\"cid:img1@local\">\r\n" +
+	"--REL\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Id: <img1@local>\r\n" +
+	"\r\n" +
+	"\x89PNG\r\n" +
+	"--REL--\r\n"
+
+func TestParse_related(t *testing.T) {
+	m, err := mail.Parse(strings.NewReader(relatedMailString))
+	if err != nil {
+		t.Fatalf("mail.Parse() = %v", err)
+	}
+
+	if m.HTMLBody != "<img src=\"cid:img1@local\">" {
+		t.Errorf("m.HTMLBody = %q, want %q", m.HTMLBody, "<img src=\"cid:img1@local\">")
+	}
+
+	if len(m.EmbeddedFiles) != 1 {
+		t.Fatalf("len(m.EmbeddedFiles) = %v, want 1", len(m.EmbeddedFiles))
+	}
+	if m.EmbeddedFiles[0].CID != "img1@local" {
+		t.Errorf("m.EmbeddedFiles[0].CID = %q, want %q", m.EmbeddedFiles[0].CID, "img1@local")
+	}
+}
+
+// Some senders reference an embedded resource by filename instead of
+// setting Content-Id: still "Content-Disposition: inline", but inside a
+// multipart/related rather than an attachment.
+const relatedByFilenameMailString = "Subject: Your Name\r\n" +
+	"Content-Type: multipart/related; boundary=REL\r\n" +
+	"\r\n" +
+	"--REL\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<img src=\"img.png\">\r\n" +
+	"--REL\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Disposition: inline; filename=img.png\r\n" +
+	"\r\n" +
+	"\x89PNG\r\n" +
+	"--REL--\r\n"
+
+func TestParse_relatedByFilename(t *testing.T) {
+	m, err := mail.Parse(strings.NewReader(relatedByFilenameMailString))
+	if err != nil {
+		t.Fatalf("mail.Parse() = %v", err)
+	}
+
+	if len(m.Attachments) != 0 {
+		t.Errorf("len(m.Attachments) = %v, want 0", len(m.Attachments))
+	}
+	if len(m.EmbeddedFiles) != 1 {
+		t.Fatalf("len(m.EmbeddedFiles) = %v, want 1", len(m.EmbeddedFiles))
+	}
+	if m.EmbeddedFiles[0].Filename != "img.png" {
+		t.Errorf("m.EmbeddedFiles[0].Filename = %q, want %q", m.EmbeddedFiles[0].Filename, "img.png")
+	}
+}
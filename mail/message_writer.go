@@ -0,0 +1,308 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"sync/atomic"
+
+	"github.com/emersion/go-message"
+)
+
+// WriteTo writes m to w as RFC 5322 bytes.
+//
+// The multipart structure is chosen to be the minimal one that fits the
+// populated fields: a single part when only one of TextBody or HTMLBody is
+// set, multipart/alternative when both are set, multipart/related wraps the
+// HTML part when it has EmbeddedFiles, and multipart/mixed wraps all of the
+// above when Attachments is non-empty. Content-Transfer-Encoding is chosen
+// automatically (plain 7bit or quoted-printable for the text and HTML bodies,
+// base64 for all attachments and embedded files), and a Message-Id is generated if m.Header
+// doesn't already have one.
+//
+// This builds directly on message.CreateWriter/CreatePart rather than the
+// existing ad-hoc mail.Writer, because mail.Writer has no notion of
+// multipart/related or Content-ID parts; it only knows how to lay out a
+// plain text/HTML alternative plus flat attachments.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	// Copy before mutating: Header holds its fields by reference, so writing
+	// into h would otherwise silently change the caller's m.Header too.
+	h := m.Header.Copy()
+	if len(m.From) > 0 {
+		h.SetAddressList("From", m.From)
+	}
+	if len(m.To) > 0 {
+		h.SetAddressList("To", m.To)
+	}
+	if len(m.Cc) > 0 {
+		h.SetAddressList("Cc", m.Cc)
+	}
+	if len(m.Bcc) > 0 {
+		h.SetAddressList("Bcc", m.Bcc)
+	}
+	if m.Subject != "" {
+		h.SetSubject(m.Subject)
+	}
+	if !m.Date.IsZero() {
+		h.SetDate(m.Date)
+	}
+	if m.InReplyTo != "" {
+		h.Set("In-Reply-To", "<"+m.InReplyTo+">")
+	}
+	if h.Get("Message-Id") == "" {
+		h.GenerateMessageID()
+	}
+	h.Set("Mime-Version", "1.0")
+
+	root, err := m.buildRoot()
+	if err != nil {
+		return cw.n, fmt.Errorf("mail: failed to build message: %v", err)
+	}
+	mergeInto(&h.Header, headerFor(root))
+
+	mw, err := message.CreateWriter(cw, h.Header)
+	if err != nil {
+		return cw.n, fmt.Errorf("mail: failed to write message: %v", err)
+	}
+	if err := writeBody(mw, root); err != nil {
+		return cw.n, fmt.Errorf("mail: failed to write message: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		return cw.n, fmt.Errorf("mail: failed to write message: %v", err)
+	}
+
+	return cw.n, nil
+}
+
+// Bytes marshals m into RFC 5322 bytes. See WriteTo for details.
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// part is an internal, write-side representation of a single MIME entity:
+// either a leaf with raw data, or a multipart container with children.
+type part struct {
+	contentType string
+	typeParams  map[string]string
+
+	disposition string
+	dispParams  map[string]string
+	contentID   string
+
+	encoding string
+	data     []byte
+
+	children []*part
+}
+
+func (m *Message) buildRoot() (*part, error) {
+	body, err := m.buildBody()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Attachments) == 0 {
+		return body, nil
+	}
+
+	mixed := &part{
+		contentType: "multipart/mixed",
+		typeParams:  map[string]string{"boundary": nextBoundary()},
+		children:    []*part{body},
+	}
+	for _, a := range m.Attachments {
+		mixed.children = append(mixed.children, attachmentPart(a))
+	}
+	return mixed, nil
+}
+
+func (m *Message) buildBody() (*part, error) {
+	hasText := m.TextBody != ""
+	hasHTML := m.HTMLBody != ""
+
+	if !hasText && !hasHTML {
+		return textPart(""), nil
+	}
+
+	var htmlPart *part
+	if hasHTML {
+		htmlPart = htmlBodyPart(m.HTMLBody)
+		if len(m.EmbeddedFiles) > 0 {
+			related := &part{
+				contentType: "multipart/related",
+				typeParams:  map[string]string{"boundary": nextBoundary()},
+				children:    []*part{htmlPart},
+			}
+			for _, f := range m.EmbeddedFiles {
+				related.children = append(related.children, embeddedPart(f))
+			}
+			htmlPart = related
+		}
+	}
+
+	switch {
+	case hasText && hasHTML:
+		return &part{
+			contentType: "multipart/alternative",
+			typeParams:  map[string]string{"boundary": nextBoundary()},
+			children:    []*part{textPart(m.TextBody), htmlPart},
+		}, nil
+	case hasHTML:
+		return htmlPart, nil
+	default:
+		return textPart(m.TextBody), nil
+	}
+}
+
+func textPart(s string) *part {
+	data := []byte(s)
+	return &part{
+		contentType: "text/plain",
+		typeParams:  map[string]string{"charset": "utf-8"},
+		encoding:    pickEncoding(data, true),
+		data:        data,
+	}
+}
+
+func htmlBodyPart(s string) *part {
+	data := []byte(s)
+	return &part{
+		contentType: "text/html",
+		typeParams:  map[string]string{"charset": "utf-8"},
+		encoding:    pickEncoding(data, true),
+		data:        data,
+	}
+}
+
+func attachmentPart(a Attachment) *part {
+	ct := a.ContentType
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	return &part{
+		contentType: ct,
+		disposition: "attachment",
+		dispParams:  map[string]string{"filename": a.Filename},
+		encoding:    pickEncoding(a.Data, false),
+		data:        a.Data,
+	}
+}
+
+func embeddedPart(f EmbeddedFile) *part {
+	ct := f.ContentType
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	return &part{
+		contentType: ct,
+		disposition: "inline",
+		dispParams:  map[string]string{"filename": f.Filename},
+		contentID:   f.CID,
+		encoding:    pickEncoding(f.Data, false),
+		data:        f.Data,
+	}
+}
+
+// pickEncoding selects a Content-Transfer-Encoding for data: plain 7bit for
+// short ASCII lines, quoted-printable for text that isn't, and base64 for
+// everything else.
+func pickEncoding(data []byte, isText bool) string {
+	if isText && isASCII(data) && !hasLongLines(data) {
+		return ""
+	}
+	if isText {
+		return "quoted-printable"
+	}
+	return "base64"
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b == 0 || b > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLongLines(data []byte) bool {
+	n := 0
+	for _, b := range data {
+		if b == '\n' {
+			n = 0
+			continue
+		}
+		if n++; n > 998 {
+			return true
+		}
+	}
+	return false
+}
+
+func headerFor(p *part) message.Header {
+	var h message.Header
+	h.Set("Content-Type", mime.FormatMediaType(p.contentType, p.typeParams))
+	if p.encoding != "" {
+		h.Set("Content-Transfer-Encoding", p.encoding)
+	}
+	if p.disposition != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType(p.disposition, p.dispParams))
+	}
+	if p.contentID != "" {
+		h.Set("Content-Id", "<"+p.contentID+">")
+	}
+	return h
+}
+
+func mergeInto(dst *message.Header, src message.Header) {
+	fields := src.Fields()
+	for fields.Next() {
+		dst.Set(fields.Key(), fields.Value())
+	}
+}
+
+func writeBody(w *message.Writer, p *part) error {
+	if len(p.children) == 0 {
+		_, err := w.Write(p.data)
+		return err
+	}
+	for _, c := range p.children {
+		cw, err := w.CreatePart(headerFor(c))
+		if err != nil {
+			return err
+		}
+		if err := writeBody(cw, c); err != nil {
+			return err
+		}
+		if err := cw.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boundaryCounter makes generated boundaries unique within a process without
+// resorting to randomness, so output is stable and easy to diff in tests.
+var boundaryCounter uint64
+
+func nextBoundary() string {
+	n := atomic.AddUint64(&boundaryCounter, 1)
+	return fmt.Sprintf("----=_NextPart_%08d", n)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,85 @@
+package mail_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message/mail"
+)
+
+func TestMessageWriteTo_textOnly(t *testing.T) {
+	m := &mail.Message{
+		Subject:  "Hello",
+		TextBody: "Hi there",
+	}
+
+	b, err := m.Bytes()
+	if err != nil {
+		t.Fatalf("m.Bytes() = %v", err)
+	}
+
+	got, err := mail.Parse(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("mail.Parse(m.Bytes()) = %v", err)
+	}
+
+	if subject, err := got.Header.Subject(); err != nil {
+		t.Errorf("got.Header.Subject() = %v", err)
+	} else if subject != "Hello" {
+		t.Errorf("got.Header.Subject() = %q, want %q", subject, "Hello")
+	}
+
+	if got.TextBody != "Hi there" {
+		t.Errorf("got.TextBody = %q, want %q", got.TextBody, "Hi there")
+	}
+}
+
+func TestMessageWriteTo_textAndHTMLWithAttachment(t *testing.T) {
+	m := &mail.Message{
+		Subject:  "Hello",
+		TextBody: "Hi there",
+		HTMLBody: "<p>Hi there</p>",
+		Attachments: []mail.Attachment{
+			{Filename: "note.txt", ContentType: "text/plain", Data: []byte("I'm Mitsuha.")},
+		},
+	}
+
+	b, err := m.Bytes()
+	if err != nil {
+		t.Fatalf("m.Bytes() = %v", err)
+	}
+
+	got, err := mail.Parse(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("mail.Parse(m.Bytes()) = %v", err)
+	}
+
+	if got.TextBody != m.TextBody {
+		t.Errorf("got.TextBody = %q, want %q", got.TextBody, m.TextBody)
+	}
+	if got.HTMLBody != m.HTMLBody {
+		t.Errorf("got.HTMLBody = %q, want %q", got.HTMLBody, m.HTMLBody)
+	}
+
+	if len(got.Attachments) != 1 {
+		t.Fatalf("len(got.Attachments) = %v, want 1", len(got.Attachments))
+	}
+	if got.Attachments[0].Filename != "note.txt" {
+		t.Errorf("got.Attachments[0].Filename = %q, want %q", got.Attachments[0].Filename, "note.txt")
+	}
+	if string(got.Attachments[0].Data) != "I'm Mitsuha." {
+		t.Errorf("got.Attachments[0].Data = %q, want %q", got.Attachments[0].Data, "I'm Mitsuha.")
+	}
+}
+
+func TestMessageWriteTo_doesNotMutateHeader(t *testing.T) {
+	m := &mail.Message{Subject: "Hello", TextBody: "Hi there"}
+
+	if _, err := m.Bytes(); err != nil {
+		t.Fatalf("m.Bytes() = %v", err)
+	}
+
+	if subject := m.Header.Get("Subject"); subject != "" {
+		t.Errorf("m.Header.Get(\"Subject\") = %q, want empty: WriteTo must not mutate the caller's Header", subject)
+	}
+}
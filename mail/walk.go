@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emersion/go-message"
+)
+
+// WalkParts walks the, possibly deeply nested, multipart/mixed,
+// multipart/alternative and multipart/related structure underlying r,
+// calling fn once for every leaf part it finds.
+//
+// path is the chain of parent media types leading to that part, outermost
+// first (e.g. []string{"multipart/mixed", "multipart/alternative"}); for a
+// non-multipart message it is empty.
+//
+// WalkParts also transparently descends into message/rfc822 sub-messages,
+// appending "message/rfc822" to path before walking the embedded message's
+// own parts, so a caller never needs to open a second Reader by hand the
+// way TestReader_nested does.
+//
+// This is the building block mail.Parse is built on, and is useful on its
+// own for mail scanners, classifiers and canonicalizers that need to know
+// the containment context of each part, not just its bytes.
+//
+// WalkParts reads r's underlying body to the end, the same way NextPart
+// does. Call either WalkParts or NextPart on a given Reader, never both:
+// interleaving them would read from two independent cursors over the same
+// single-pass stream.
+func (r *Reader) WalkParts(fn func(path []string, p *Part) error) error {
+	return walkEntity(r.e, nil, fn)
+}
+
+func walkEntity(e *message.Entity, path []string, fn func(path []string, p *Part) error) error {
+	mediaType, _, _ := e.Header.ContentType()
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		childPath := append(append([]string{}, path...), mediaType)
+		mr := e.MultipartReader()
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if err := walkEntity(p, childPath, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	case mediaType == "message/rfc822":
+		childPath := append(append([]string{}, path...), mediaType)
+		inner, err := message.Read(e.Body)
+		if message.IsUnknownCharset(err) {
+			// Keep going: the body is still readable.
+		} else if err != nil {
+			return err
+		}
+		return walkEntity(inner, childPath, fn)
+	default:
+		return fn(path, &Part{Header: partHeaderFor(e), Body: e.Body})
+	}
+}
+
+func partHeaderFor(e *message.Entity) PartHeader {
+	disp, _, _ := e.Header.ContentDisposition()
+	if disp == "attachment" {
+		return &AttachmentHeader{e.Header}
+	}
+	return &InlineHeader{e.Header}
+}
+
+// partContentType returns a Part's Content-Type. PartHeader itself only
+// declares Add/Del/Get/Set, so callers can't call ContentType() on p.Header
+// directly; they need the concrete InlineHeader or AttachmentHeader it's
+// always one of, same as the type switch in ExampleReader.
+func partContentType(h PartHeader) (string, map[string]string, error) {
+	switch h := h.(type) {
+	case *InlineHeader:
+		return h.ContentType()
+	case *AttachmentHeader:
+		return h.ContentType()
+	default:
+		return "", nil, fmt.Errorf("mail: unexpected part header type %T", h)
+	}
+}
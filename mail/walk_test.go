@@ -0,0 +1,70 @@
+package mail_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message/mail"
+)
+
+const walkPartsMailString = "Subject: Your Name\r\n" +
+	"Content-Type: multipart/mixed; boundary=MIXED\r\n" +
+	"\r\n" +
+	"--MIXED\r\n" +
+	"Content-Type: multipart/alternative; boundary=ALT\r\n" +
+	"\r\n" +
+	"--ALT\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Who are you?\r\n" +
+	"--ALT--\r\n" +
+	"--MIXED\r\n" +
+	"Content-Disposition: attachment; filename=note.txt\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"I'm Mitsuha.\r\n" +
+	"--MIXED--\r\n"
+
+func TestReaderWalkParts(t *testing.T) {
+	mr, err := mail.CreateReader(strings.NewReader(walkPartsMailString))
+	if err != nil {
+		t.Fatalf("mail.CreateReader() = %v", err)
+	}
+	defer mr.Close()
+
+	var gotPaths [][]string
+	var gotBodies []string
+	err = mr.WalkParts(func(path []string, p *mail.Part) error {
+		gotPaths = append(gotPaths, path)
+		b, err := ioutil.ReadAll(p.Body)
+		if err != nil {
+			return err
+		}
+		gotBodies = append(gotBodies, string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("mr.WalkParts() = %v", err)
+	}
+
+	wantPaths := [][]string{
+		{"multipart/mixed", "multipart/alternative"},
+		{"multipart/mixed"},
+	}
+	if len(gotPaths) != len(wantPaths) {
+		t.Fatalf("got %v paths, want %v", len(gotPaths), len(wantPaths))
+	}
+	for i, want := range wantPaths {
+		if strings.Join(gotPaths[i], ",") != strings.Join(want, ",") {
+			t.Errorf("gotPaths[%v] = %v, want %v", i, gotPaths[i], want)
+		}
+	}
+
+	wantBodies := []string{"Who are you?", "I'm Mitsuha."}
+	for i, want := range wantBodies {
+		if gotBodies[i] != want {
+			t.Errorf("gotBodies[%v] = %q, want %q", i, gotBodies[i], want)
+		}
+	}
+}